@@ -0,0 +1,204 @@
+package gtls
+
+import (
+	"context"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/acme"
+)
+
+// Identifier names a subject an ACMEIssuer should obtain a certificate for.
+type Identifier struct {
+	// Type is "dns" or "ip".
+	Type  string
+	Value string
+}
+
+// DNSIdentifier is a convenience constructor for a "dns" Identifier.
+func DNSIdentifier(name string) Identifier {
+	return Identifier{Type: "dns", Value: name}
+}
+
+// ChallengeSolver provisions and tears down the resources needed to satisfy
+// an ACME challenge for an identifier, e.g. serving an HTTP-01 token or
+// creating a DNS-01 TXT record.
+type ChallengeSolver interface {
+	// Present makes the key authorization for chal available however the
+	// challenge type requires (HTTP response, DNS record, etc).
+	Present(ctx context.Context, identifier Identifier, chal *acme.Challenge, keyAuth string) error
+	// CleanUp removes whatever Present set up.
+	CleanUp(ctx context.Context, identifier Identifier, chal *acme.Challenge) error
+	// ChallengeType is the ACME challenge type this solver handles, e.g.
+	// "http-01" or "dns-01".
+	ChallengeType() string
+}
+
+// ACMEIssuer drives certificate issuance against an ACME directory (Let's
+// Encrypt, ZeroSSL, step-ca, or any other RFC 8555 CA), as a complement to
+// the built-in CA used for MITM leaves. Issued certificates can be fed into
+// the same CertStore that serves MITM leaves.
+type ACMEIssuer struct {
+	client  *acme.Client
+	account *acme.Account
+	solvers map[string]ChallengeSolver
+}
+
+// NewACMEClient creates an ACMEIssuer for directoryURL using accountKey as
+// the ACME account key. No account is registered until Register is called;
+// contacts are mailto:/tel: URIs passed along at that point.
+func NewACMEClient(directoryURL string, accountKey crypto.Signer, contacts ...string) *ACMEIssuer {
+	return &ACMEIssuer{
+		client: &acme.Client{
+			Key:          accountKey,
+			DirectoryURL: directoryURL,
+		},
+		account: &acme.Account{Contact: contacts},
+		solvers: make(map[string]ChallengeSolver),
+	}
+}
+
+// UseSolver registers a ChallengeSolver for its challenge type, overwriting
+// any solver previously registered for that type.
+func (i *ACMEIssuer) UseSolver(solver ChallengeSolver) {
+	i.solvers[solver.ChallengeType()] = solver
+}
+
+// WithEAB attaches External Account Binding credentials required by CAs such
+// as ZeroSSL. It must be called before Register.
+func (i *ACMEIssuer) WithEAB(kid string, key []byte) *ACMEIssuer {
+	i.account.ExternalAccountBinding = &acme.ExternalAccountBinding{KID: kid, Key: key}
+	return i
+}
+
+// Register creates the ACME account, agreeing to the CA's terms of service
+// automatically. If the account key is already registered, the CA's
+// acme.ErrAccountAlreadyExists is treated as success: the client's KID is
+// populated as a side effect of the failed attempt, so the existing account
+// is fetched through it instead of erroring out.
+func (i *ACMEIssuer) Register(ctx context.Context) error {
+	account, err := i.client.Register(ctx, i.account, acme.AcceptTOS)
+	if err != nil {
+		if !errors.Is(err, acme.ErrAccountAlreadyExists) {
+			return err
+		}
+		account, err = i.client.GetReg(ctx, string(i.client.KID))
+		if err != nil {
+			return err
+		}
+	}
+	i.account = account
+	return nil
+}
+
+// NewOrder requests a new certificate order for identifiers and drives each
+// required authorization through whichever registered ChallengeSolver
+// handles it, returning the order once all authorizations are valid.
+func (i *ACMEIssuer) NewOrder(ctx context.Context, identifiers []Identifier) (*acme.Order, error) {
+	authzIDs := make([]acme.AuthzID, len(identifiers))
+	for n, id := range identifiers {
+		authzIDs[n] = acme.AuthzID{Type: id.Type, Value: id.Value}
+	}
+	order, err := i.client.AuthorizeOrder(ctx, authzIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, authzURL := range order.AuthzURLs {
+		if err := i.Authorize(ctx, identifiers, authzURL); err != nil {
+			return nil, err
+		}
+	}
+	return i.client.GetOrder(ctx, order.URI)
+}
+
+// Authorize fetches a pending authorization and satisfies it using whichever
+// registered ChallengeSolver matches one of its offered challenges, waiting
+// for the CA to confirm the authorization as valid before returning.
+func (i *ACMEIssuer) Authorize(ctx context.Context, identifiers []Identifier, authzURL string) error {
+	authz, err := i.client.GetAuthorization(ctx, authzURL)
+	if err != nil {
+		return err
+	}
+	if authz.Status == acme.StatusValid {
+		return nil
+	}
+	var identifier Identifier
+	for _, id := range identifiers {
+		if id.Value == authz.Identifier.Value {
+			identifier = id
+			break
+		}
+	}
+	for _, chal := range authz.Challenges {
+		solver, ok := i.solvers[chal.Type]
+		if !ok {
+			continue
+		}
+		keyAuth, err := i.challengeKeyAuth(chal)
+		if err != nil {
+			return err
+		}
+		if err := solver.Present(ctx, identifier, chal, keyAuth); err != nil {
+			return err
+		}
+		defer solver.CleanUp(ctx, identifier, chal)
+		if _, err := i.client.Accept(ctx, chal); err != nil {
+			return err
+		}
+		if _, err := i.client.WaitAuthorization(ctx, authz.URI); err != nil {
+			return err
+		}
+		return nil
+	}
+	return &acme.Error{ProblemType: "gtls:noSolver", Detail: "no ChallengeSolver registered for any offered challenge type"}
+}
+
+// challengeKeyAuth computes the value a ChallengeSolver must publish to
+// satisfy chal: the raw key authorization for http-01, or its SHA-256/
+// base64url digest for dns-01, per RFC 8555 §8.
+func (i *ACMEIssuer) challengeKeyAuth(chal *acme.Challenge) (string, error) {
+	switch chal.Type {
+	case "http-01":
+		return i.client.HTTP01ChallengeResponse(chal.Token)
+	case "dns-01":
+		return i.client.DNS01ChallengeRecord(chal.Token)
+	default:
+		return "", fmt.Errorf("gtls: unsupported ACME challenge type %q", chal.Type)
+	}
+}
+
+// Finalize submits csr against order's finalize URL once all its
+// authorizations are valid, returning the DER-encoded certificate chain.
+func (i *ACMEIssuer) Finalize(ctx context.Context, order *acme.Order, csr *x509.CertificateRequest) ([][]byte, error) {
+	der, _, err := i.client.CreateOrderCert(ctx, order.FinalizeURL, csr.Raw, true)
+	return der, err
+}
+
+// FetchCert downloads the DER-encoded certificate chain for an already
+// finalized order from certURL.
+func (i *ACMEIssuer) FetchCert(ctx context.Context, certURL string) ([][]byte, error) {
+	return i.client.FetchCert(ctx, certURL, true)
+}
+
+// CertificateFor builds a tls.Certificate from a DER-encoded chain as
+// returned by Finalize/FetchCert and the private key the CSR was generated
+// with. The result can be passed to (*CertStore).Add, so a single CertStore
+// (and the tls.Config built from it) serves both ACME-issued certs and
+// on-the-fly MITM leaves.
+func CertificateFor(der [][]byte, key crypto.Signer) (tls.Certificate, error) {
+	if len(der) == 0 {
+		return tls.Certificate{}, errors.New("gtls: empty certificate chain")
+	}
+	leaf, err := x509.ParseCertificate(der[0])
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{
+		Certificate: der,
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}, nil
+}
@@ -0,0 +1,106 @@
+package gtls
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// LoadOrCreateCA loads a CA keypair from certPath/keyPath if both files
+// already exist, otherwise it generates a new CA via opts and atomically
+// writes the pair to disk (the key with 0600 permissions) so future calls
+// reuse it instead of every deployment minting its own trust root.
+func LoadOrCreateCA(certPath, keyPath string, opts CAOpts) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	if fileExists(certPath) && fileExists(keyPath) {
+		return loadCAFromDisk(certPath, keyPath)
+	}
+	cert, signer, err := GenerateCA(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, ok := signer.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, nil, fmt.Errorf("gtls: LoadOrCreateCA requires an ECDSA KeyAlgorithm, got %T", signer)
+	}
+	if err := writeCA(certPath, keyPath, cert, key); err != nil {
+		return nil, nil, err
+	}
+	return cert, key, nil
+}
+
+func loadCAFromDisk(certPath, keyPath string) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := LoadCert(certPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := LoadCertKey(keyPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, key, nil
+}
+
+func writeCA(certPath, keyPath string, cert *x509.Certificate, key *ecdsa.PrivateKey) error {
+	keyData, err := GetCertKeyData(key)
+	if err != nil {
+		return err
+	}
+	if err := atomicWriteFile(certPath, GetCertData(cert), 0o644); err != nil {
+		return err
+	}
+	return atomicWriteFile(keyPath, keyData, 0o600)
+}
+
+// atomicWriteFile writes data to a temp file in the same directory as path
+// and renames it into place, so readers never observe a partially written
+// file.
+func atomicWriteFile(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".gtls-*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// ExportCA returns an http.HandlerFunc that serves cert as a
+// application/x-x509-ca-cert download, so MITM clients can bootstrap trust
+// by fetching it from a known path instead of needing the file out of band.
+func ExportCA(cert *x509.Certificate) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-x509-ca-cert")
+		w.Header().Set("Content-Disposition", `attachment; filename="ca.crt"`)
+		w.Write(GetCertData(cert))
+	}
+}
@@ -0,0 +1,33 @@
+//go:build darwin
+
+package gtls
+
+import (
+	"crypto/x509"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// InstallCATrust adds cert to the macOS System keychain as a trusted root via
+// the security command. It typically requires administrator privileges.
+func InstallCATrust(cert *x509.Certificate) error {
+	f, err := os.CreateTemp("", "gtls-ca-*.crt")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(GetCertData(cert)); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	out, err := exec.Command("security", "add-trusted-cert", "-d", "-r", "trustRoot",
+		"-k", "/Library/Keychains/System.keychain", f.Name()).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("gtls: security add-trusted-cert: %w: %s", err, out)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+//go:build linux
+
+package gtls
+
+import (
+	"crypto/x509"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// InstallCATrust installs cert into the system trust store under
+// /usr/local/share/ca-certificates and refreshes it via update-ca-certificates.
+// It typically requires root privileges.
+func InstallCATrust(cert *x509.Certificate) error {
+	path := filepath.Join("/usr/local/share/ca-certificates", caTrustFileName(cert))
+	if err := os.WriteFile(path, GetCertData(cert), 0o644); err != nil {
+		return err
+	}
+	out, err := exec.Command("update-ca-certificates").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("gtls: update-ca-certificates: %w: %s", err, out)
+	}
+	return nil
+}
+
+func caTrustFileName(cert *x509.Certificate) string {
+	return sanitizeCacheName(cert.Subject.CommonName) + ".crt"
+}
@@ -0,0 +1,32 @@
+//go:build windows
+
+package gtls
+
+import (
+	"crypto/x509"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// InstallCATrust adds cert to the Windows "Root" certificate store via
+// certutil. It typically requires an elevated (administrator) process.
+func InstallCATrust(cert *x509.Certificate) error {
+	f, err := os.CreateTemp("", "gtls-ca-*.crt")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(GetCertData(cert)); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	out, err := exec.Command("certutil", "-addstore", "-f", "Root", f.Name()).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("gtls: certutil -addstore: %w: %s", err, out)
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package gtls
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"time"
+)
+
+// KeyAlgorithm selects the key type GenerateCA/GenerateCert generate.
+type KeyAlgorithm int
+
+const (
+	ECDSAP256 KeyAlgorithm = iota
+	ECDSAP384
+	Ed25519
+	RSA2048
+	RSA3072
+	RSA4096
+)
+
+// CAOpts configures GenerateCA.
+type CAOpts struct {
+	KeyAlgorithm KeyAlgorithm
+
+	// NotBefore/NotAfter pin an explicit validity window. If NotAfter is
+	// zero, Validity is used instead; if both are zero the CA defaults to
+	// the historical 1000-year validity.
+	NotBefore time.Time
+	NotAfter  time.Time
+	Validity  time.Duration
+
+	Subject pkix.Name
+
+	// PermittedDNSDomains sets the CA's name-constraints extension,
+	// restricting which DNS names certs signed by it may cover.
+	PermittedDNSDomains []string
+
+	// MaxPathLen limits how many intermediate CAs may follow this one.
+	// Zero means "may only sign leaf certs".
+	MaxPathLen int
+}
+
+// CertOpts configures GenerateCert.
+type CertOpts struct {
+	KeyAlgorithm KeyAlgorithm
+
+	NotBefore time.Time
+	NotAfter  time.Time
+	Validity  time.Duration
+
+	// Subject overrides the leaf's subject. If it has neither a
+	// CommonName nor an Organization, it is ignored and the issuing CA's
+	// subject is reused with CommonName replaced by the first DNS name or
+	// IP SAN.
+	Subject pkix.Name
+
+	DNSNames       []string
+	IPAddresses    []net.IP
+	URIs           []*url.URL
+	EmailAddresses []string
+
+	// ExtKeyUsage defaults to ServerAuth+ClientAuth when empty.
+	ExtKeyUsage []x509.ExtKeyUsage
+
+	// PermittedDNSDomains, when set, is consulted by SignCSR to reject
+	// CSRs whose DNS SANs fall outside these domains. Unused elsewhere.
+	PermittedDNSDomains []string
+
+	// AllowEmailAndURISANs lets SignCSR carry a CSR's URI and email SANs
+	// through to the issued leaf. Off by default since most MITM/ACME
+	// leaves have no business asserting them.
+	AllowEmailAndURISANs bool
+}
+
+func (o CAOpts) window() (time.Time, time.Time) {
+	notBefore := o.NotBefore
+	if notBefore.IsZero() {
+		notBefore = time.Now()
+	}
+	notAfter := o.NotAfter
+	if notAfter.IsZero() {
+		if o.Validity != 0 {
+			notAfter = notBefore.Add(o.Validity)
+		} else {
+			notAfter = notBefore.AddDate(1000, 0, 0)
+		}
+	}
+	return notBefore, notAfter
+}
+
+func (o CertOpts) window() (time.Time, time.Time) {
+	notBefore := o.NotBefore
+	if notBefore.IsZero() {
+		notBefore = time.Now()
+	}
+	notAfter := o.NotAfter
+	if notAfter.IsZero() {
+		if o.Validity != 0 {
+			notAfter = notBefore.Add(o.Validity)
+		} else {
+			notAfter = notBefore.AddDate(1000, 0, 0)
+		}
+	}
+	return notBefore, notAfter
+}
+
+func (o CertOpts) extKeyUsage() []x509.ExtKeyUsage {
+	if len(o.ExtKeyUsage) > 0 {
+		return o.ExtKeyUsage
+	}
+	return []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+}
+
+// generateKey produces a fresh private key of the requested algorithm.
+func generateKey(alg KeyAlgorithm) (crypto.Signer, error) {
+	switch alg {
+	case ECDSAP256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case ECDSAP384:
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case Ed25519:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	case RSA2048:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case RSA3072:
+		return rsa.GenerateKey(rand.Reader, 3072)
+	case RSA4096:
+		return rsa.GenerateKey(rand.Reader, 4096)
+	default:
+		return nil, fmt.Errorf("gtls: unsupported key algorithm %d", alg)
+	}
+}
+
+// randomSerial returns a cryptographically random 128-bit serial number, as
+// opposed to a predictable, collision-prone Unix timestamp.
+func randomSerial() (*big.Int, error) {
+	max := new(big.Int).Lsh(big.NewInt(1), 128)
+	return rand.Int(rand.Reader, max)
+}
+
+// GenerateCA creates a new self-signed root CA certificate and key according
+// to opts. It is the canonical entry point for minting CAs; CreateRootCert
+// remains as a backwards-compatible helper that shares its certificate
+// builder but takes an existing key.
+func GenerateCA(opts CAOpts) (*x509.Certificate, crypto.Signer, error) {
+	key, err := generateKey(opts.KeyAlgorithm)
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := buildCACert(key, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, key, nil
+}
+
+func buildCACert(key crypto.Signer, opts CAOpts) (*x509.Certificate, error) {
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+	notBefore, notAfter := opts.window()
+	template := &x509.Certificate{
+		Version:                     3,
+		SerialNumber:                serial,
+		Subject:                     opts.Subject,
+		NotBefore:                   notBefore,
+		NotAfter:                    notAfter,
+		BasicConstraintsValid:       true,
+		IsCA:                        true,
+		MaxPathLen:                  opts.MaxPathLen,
+		MaxPathLenZero:              opts.MaxPathLen == 0,
+		KeyUsage:                    x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		PermittedDNSDomains:         opts.PermittedDNSDomains,
+		PermittedDNSDomainsCritical: len(opts.PermittedDNSDomains) > 0,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(der)
+}
+
+// GenerateCert mints a new leaf certificate signed by rootCert/rootKey,
+// generating a fresh key of its own per opts.KeyAlgorithm. It is the
+// canonical entry point for minting leaves; CreateCertWithCN remains as a
+// backwards-compatible helper that shares its certificate builder but reuses
+// an existing key.
+func GenerateCert(rootCert *x509.Certificate, rootKey crypto.Signer, opts CertOpts) (*x509.Certificate, crypto.Signer, error) {
+	key, err := generateKey(opts.KeyAlgorithm)
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := buildLeafCert(rootCert, rootKey, key.Public(), opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, key, nil
+}
+
+func buildLeafCert(rootCert *x509.Certificate, rootKey crypto.Signer, pub crypto.PublicKey, opts CertOpts) (*x509.Certificate, error) {
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+	notBefore, notAfter := opts.window()
+	subject := opts.Subject
+	if subject.CommonName == "" && len(subject.Organization) == 0 {
+		subject = rootCert.Subject
+		switch {
+		case len(opts.DNSNames) > 0:
+			subject.CommonName = opts.DNSNames[0]
+		case len(opts.IPAddresses) > 0:
+			subject.CommonName = opts.IPAddresses[0].String()
+		}
+	}
+	template := &x509.Certificate{
+		Version:               3,
+		SerialNumber:          serial,
+		Subject:               subject,
+		DNSNames:              opts.DNSNames,
+		IPAddresses:           opts.IPAddresses,
+		URIs:                  opts.URIs,
+		EmailAddresses:        opts.EmailAddresses,
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		BasicConstraintsValid: true,
+		IsCA:                  false,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:           opts.extKeyUsage(),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, rootCert, pub, rootKey)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(der)
+}
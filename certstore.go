@@ -0,0 +1,240 @@
+package gtls
+
+import (
+	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// certRenewalMargin is how far ahead of a cached leaf's expiry CertStore
+// discards it and mints a replacement instead of handing out a cert that is
+// about to stop being valid.
+const certRenewalMargin = 24 * time.Hour
+
+// leafValidity is how long a generated MITM leaf is valid for, short enough
+// that certRenewalMargin eviction actually fires instead of leaves living
+// for the CA's own ~1000-year validity.
+const leafValidity = 7 * 24 * time.Hour
+
+// maxCacheEntries bounds the number of distinct SNIs CertStore will hold at
+// once. chi.ServerName is attacker-controlled; without a cap, probing many
+// hostnames through a MITM listener would grow the cache (and its on-disk
+// mirror) without limit.
+const maxCacheEntries = 10000
+
+// CertStore generates and caches MITM leaf certificates on demand, keyed by
+// SNI/CN, so a proxy doesn't mint a fresh leaf on every handshake. It is safe
+// for concurrent use.
+type CertStore struct {
+	mu    sync.RWMutex
+	cache map[string]*tls.Certificate
+	order []string // insertion order of cache, for FIFO eviction once maxCacheEntries is hit
+
+	rootCert *x509.Certificate
+	rootKey  *ecdsa.PrivateKey
+
+	// dir, when non-empty, is a directory where generated leaves are
+	// persisted as PEM pairs and reloaded from on the next lookup.
+	dir string
+}
+
+// NewCertStore returns a CertStore that mints leaves signed by rootCert/rootKey.
+// If dir is non-empty, generated leaves are also written there and reused
+// across process restarts.
+func NewCertStore(rootCert *x509.Certificate, rootKey *ecdsa.PrivateKey, dir string) *CertStore {
+	return &CertStore{
+		cache:    make(map[string]*tls.Certificate),
+		rootCert: rootCert,
+		rootKey:  rootKey,
+		dir:      dir,
+	}
+}
+
+// CACert returns the root certificate leaves in this store are signed by.
+func (s *CertStore) CACert() *x509.Certificate {
+	return s.rootCert
+}
+
+// TLSConfig returns a server-ready *tls.Config backed by this store, suitable
+// for handling MITM'd connections for any SNI.
+func (s *CertStore) TLSConfig() *tls.Config {
+	return &tls.Config{GetCertificate: s.GetCertificate}
+}
+
+// GetCertificate implements the signature expected by tls.Config.GetCertificate.
+// It serves a cached leaf for chi.ServerName when one exists and isn't near
+// expiry, otherwise it generates, caches, and returns a new one.
+func (s *CertStore) GetCertificate(chi *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	name := chi.ServerName
+	if name == "" {
+		name = "127.0.0.1"
+	}
+	if cert := s.lookup(name); cert != nil {
+		return cert, nil
+	}
+	return s.generate(name)
+}
+
+func (s *CertStore) lookup(name string) *tls.Certificate {
+	s.mu.RLock()
+	cert, ok := s.cache[name]
+	s.mu.RUnlock()
+	if ok && !certNearExpiry(cert) {
+		return cert
+	}
+	if s.dir == "" {
+		return nil
+	}
+	loaded, err := loadPersistedCert(s.dir, name)
+	if err != nil || loaded == nil || certNearExpiry(loaded) {
+		return nil
+	}
+	s.mu.Lock()
+	s.store(name, loaded)
+	s.mu.Unlock()
+	return loaded
+}
+
+func (s *CertStore) generate(name string) (*tls.Certificate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Re-check under the write lock: another goroutine may have already
+	// generated this name while we were waiting.
+	if cert, ok := s.cache[name]; ok && !certNearExpiry(cert) {
+		return cert, nil
+	}
+	opts := CertOpts{Validity: leafValidity}
+	if ip, ipType := ParseHost(name); ipType == 0 {
+		opts.DNSNames = []string{name}
+	} else {
+		opts.IPAddresses = []net.IP{ip}
+	}
+	leaf, signer, err := GenerateCert(s.rootCert, s.rootKey, opts)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := signer.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("gtls: CertStore requires an ECDSA leaf key, got %T", signer)
+	}
+	tlsCert, err := CreateTlsCert(leaf, key)
+	if err != nil {
+		return nil, err
+	}
+	if s.dir != "" {
+		if err := persistCert(s.dir, name, &tlsCert); err != nil {
+			return nil, err
+		}
+	}
+	s.store(name, &tlsCert)
+	return &tlsCert, nil
+}
+
+// Add inserts an externally obtained certificate (e.g. one issued through
+// ACMEIssuer) into the store under name, so it is served from the same
+// tls.Config as generated MITM leaves.
+func (s *CertStore) Add(name string, cert *tls.Certificate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store(name, cert)
+}
+
+// store records cert under name, evicting the oldest entry first if doing so
+// would exceed maxCacheEntries. Callers must hold s.mu for writing.
+func (s *CertStore) store(name string, cert *tls.Certificate) {
+	if _, exists := s.cache[name]; !exists {
+		s.order = append(s.order, name)
+		if len(s.order) > maxCacheEntries {
+			var oldest string
+			oldest, s.order = s.order[0], s.order[1:]
+			delete(s.cache, oldest)
+			if s.dir != "" {
+				base := filepath.Join(s.dir, sanitizeCacheName(oldest))
+				os.Remove(base + ".crt")
+				os.Remove(base + ".key")
+			}
+		}
+	}
+	s.cache[name] = cert
+}
+
+func certNearExpiry(cert *tls.Certificate) bool {
+	if cert == nil || len(cert.Certificate) == 0 {
+		return true
+	}
+	leaf := cert.Leaf
+	if leaf == nil {
+		parsed, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return true
+		}
+		leaf = parsed
+	}
+	return time.Until(leaf.NotAfter) < certRenewalMargin
+}
+
+func persistCert(dir, name string, cert *tls.Certificate) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	base := filepath.Join(dir, sanitizeCacheName(name))
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(base+".crt", GetCertData(leaf), 0o644); err != nil {
+		return err
+	}
+	key, ok := cert.PrivateKey.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil
+	}
+	keyData, err := GetCertKeyData(key)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(base+".key", keyData, 0o600)
+}
+
+// loadPersistedCert reads back a PEM pair previously written by persistCert.
+// It returns (nil, nil) when no cached files exist for name yet.
+func loadPersistedCert(dir, name string) (*tls.Certificate, error) {
+	base := filepath.Join(dir, sanitizeCacheName(name))
+	certPEM, err := os.ReadFile(base + ".crt")
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	keyPEM, err := os.ReadFile(base + ".key")
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	tlsCert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	return &tlsCert, nil
+}
+
+func sanitizeCacheName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
@@ -0,0 +1,108 @@
+package gtls
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+// SignCSR validates csr and mints a leaf certificate from it, signed by
+// rootCert/key. Unlike CreateCertWithCert it canonicalizes the CSR's SANs:
+// DNS names and IP addresses are deduplicated and sorted, and a CSR that
+// carries a CommonName but no matching SAN has one folded in as a DNS name
+// or IP SAN depending on what net.ParseIP makes of it. URIs and email
+// addresses are preserved only when opts.AllowEmailAndURISANs is set. If
+// opts.PermittedDNSDomains is non-empty, any DNS SAN outside those domains
+// is rejected.
+func SignCSR(rootCert *x509.Certificate, key *ecdsa.PrivateKey, csr *x509.CertificateRequest, opts CertOpts) (*x509.Certificate, error) {
+	if err := csr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("gtls: invalid CSR signature: %w", err)
+	}
+
+	dnsNames, ipAddrs := canonicalizeSANs(csr)
+	if err := checkPermittedDNSDomains(dnsNames, opts.PermittedDNSDomains); err != nil {
+		return nil, err
+	}
+
+	leafOpts := opts
+	leafOpts.DNSNames = dnsNames
+	leafOpts.IPAddresses = ipAddrs
+	if opts.AllowEmailAndURISANs {
+		leafOpts.URIs = csr.URIs
+		leafOpts.EmailAddresses = csr.EmailAddresses
+	} else {
+		leafOpts.URIs = nil
+		leafOpts.EmailAddresses = nil
+	}
+	if leafOpts.Subject.CommonName == "" {
+		leafOpts.Subject = csr.Subject
+	}
+
+	return buildLeafCert(rootCert, key, csr.PublicKey, leafOpts)
+}
+
+// canonicalizeSANs returns csr's DNS names and IP addresses, deduplicated,
+// sorted, and with csr.Subject.CommonName folded in as a DNS name or IP SAN
+// (whichever net.ParseIP says it is) when it isn't already covered.
+func canonicalizeSANs(csr *x509.CertificateRequest) ([]string, []net.IP) {
+	dnsSet := make(map[string]bool, len(csr.DNSNames))
+	for _, name := range csr.DNSNames {
+		dnsSet[name] = true
+	}
+	ipSet := make(map[string]net.IP, len(csr.IPAddresses))
+	for _, ip := range csr.IPAddresses {
+		ipSet[ip.String()] = ip
+	}
+
+	if cn := csr.Subject.CommonName; cn != "" {
+		if ip := net.ParseIP(cn); ip != nil {
+			ipSet[ip.String()] = ip
+		} else if !dnsSet[cn] {
+			dnsSet[cn] = true
+		}
+	}
+
+	dnsNames := make([]string, 0, len(dnsSet))
+	for name := range dnsSet {
+		dnsNames = append(dnsNames, name)
+	}
+	sort.Strings(dnsNames)
+
+	ips := make([]net.IP, 0, len(ipSet))
+	for _, ip := range ipSet {
+		ips = append(ips, ip)
+	}
+	sort.Slice(ips, func(i, j int) bool { return bytes.Compare(ips[i].To16(), ips[j].To16()) < 0 })
+
+	return dnsNames, ips
+}
+
+// checkPermittedDNSDomains rejects names that fall outside permitted, which
+// may contain bare domains ("example.com") or wildcard-style suffixes
+// (".example.com", matching any subdomain). An empty permitted list allows
+// anything.
+func checkPermittedDNSDomains(names, permitted []string) error {
+	if len(permitted) == 0 {
+		return nil
+	}
+	for _, name := range names {
+		if !dnsNamePermitted(name, permitted) {
+			return fmt.Errorf("gtls: CSR SAN %q is outside the permitted DNS domains %v", name, permitted)
+		}
+	}
+	return nil
+}
+
+func dnsNamePermitted(name string, permitted []string) bool {
+	for _, domain := range permitted {
+		domain = strings.TrimPrefix(domain, ".")
+		if name == domain || strings.HasSuffix(name, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
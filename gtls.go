@@ -10,7 +10,6 @@ import (
 	_ "embed"
 	"encoding/pem"
 	"errors"
-	"math/big"
 	"net"
 	"net/url"
 	"strconv"
@@ -78,6 +77,9 @@ func GetServerName(addr string) string {
 	return host
 }
 
+// CreateRootCert builds a CA certificate with the same certificate builder as
+// GenerateCA, but for an already-generated key and the historical Gospider
+// CA defaults.
 func CreateRootCert(key *ecdsa.PrivateKey) (*x509.Certificate, error) {
 	beforeDate, err := time.ParseInLocation(time.DateOnly, "2023-03-20", time.Local)
 	if err != nil {
@@ -87,9 +89,7 @@ func CreateRootCert(key *ecdsa.PrivateKey) (*x509.Certificate, error) {
 	if err != nil {
 		return nil, err
 	}
-	rootCsr := &x509.Certificate{
-		Version:      3,
-		SerialNumber: big.NewInt(time.Now().Unix()),
+	return buildCACert(key, CAOpts{
 		Subject: pkix.Name{
 			Country:            []string{"CN"},
 			Province:           []string{"Shanghai"},
@@ -98,80 +98,46 @@ func CreateRootCert(key *ecdsa.PrivateKey) (*x509.Certificate, error) {
 			OrganizationalUnit: []string{"GoSpiderProxy"},
 			CommonName:         "Gospider Root CA",
 		},
-		NotBefore:             beforeDate,
-		NotAfter:              afterDate,
-		BasicConstraintsValid: true,
-		IsCA:                  true,
-		MaxPathLen:            1,
-		MaxPathLenZero:        false,
-		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
-	}
-	rootDer, err := x509.CreateCertificate(rand.Reader, rootCsr, rootCsr, key.Public(), key)
-	if err != nil {
-		return nil, err
-	}
-	return x509.ParseCertificate(rootDer)
+		NotBefore:  beforeDate,
+		NotAfter:   afterDate,
+		MaxPathLen: 1,
+	})
 }
 
 func CreateCertKey() (*ecdsa.PrivateKey, error) {
 	return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 }
 
+// CreateCertWithCN builds a leaf with the same certificate builder as
+// GenerateCert, but instead of generating a fresh key it reuses key as
+// both the leaf's signing key and its own keypair, mirroring the original
+// behavior of this function.
 func CreateCertWithCN(rootCert *x509.Certificate, key *ecdsa.PrivateKey, commonName string) (*x509.Certificate, error) {
-	csr := &x509.Certificate{
-		Version:               3,
-		SerialNumber:          big.NewInt(time.Now().Unix()),
-		Subject:               rootCert.Subject,
-		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
-		NotBefore:             time.Now(),
-		NotAfter:              time.Now().AddDate(1000, 0, 0),
-		BasicConstraintsValid: true,
-		IsCA:                  false,
-		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
-		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
-	}
-	csr.IPAddresses = []net.IP{}
+	opts := CertOpts{}
 	if commonName != "" {
 		if ip, ipType := ParseHost(commonName); ipType == 0 {
-			csr.Subject.CommonName = commonName
-			csr.DNSNames = []string{commonName}
+			opts.DNSNames = []string{commonName}
 		} else {
-			csr.IPAddresses = append(csr.IPAddresses, ip)
+			opts.IPAddresses = []net.IP{ip}
 		}
 	}
-	der, err := x509.CreateCertificate(rand.Reader, csr, rootCert, key.Public(), key)
-	if err != nil {
-		return nil, err
-	}
-	return x509.ParseCertificate(der)
+	return buildLeafCert(rootCert, key, key.Public(), opts)
 }
 
+// CreateCertWithCert builds a leaf with the same certificate builder as
+// GenerateCert, copying the DNS/IP SANs from an existing certificate onto a
+// new leaf issued by rootCert/key and reusing key as the leaf's own keypair. Earlier versions of this function rewrote rootCert.Subject
+// in place, which corrupted the root CA's subject for every call after the
+// first; it now leaves rootCert untouched.
 func CreateCertWithCert(rootCert *x509.Certificate, key *ecdsa.PrivateKey, preCert *x509.Certificate) (*x509.Certificate, error) {
-	if preCert.DNSNames == nil && preCert.Subject.CommonName != "" {
-		preCert.DNSNames = []string{preCert.Subject.CommonName}
-	}
-	rootCert.Subject.CommonName = preCert.Subject.CommonName
-	csr := &x509.Certificate{
-		Version:               3,
-		SerialNumber:          big.NewInt(time.Now().Unix()),
-		Subject:               rootCert.Subject,
-		DNSNames:              preCert.DNSNames,
-		IPAddresses:           preCert.IPAddresses,
-		NotBefore:             time.Now(),
-		NotAfter:              time.Now().AddDate(1000, 0, 0),
-		BasicConstraintsValid: true,
-		IsCA:                  false,
-		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
-		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
-	}
-	if len(preCert.DNSNames) > 0 {
-		csr.Subject.CommonName = preCert.DNSNames[0]
-	}
-	der, err := x509.CreateCertificate(rand.Reader, csr, rootCert, key.Public(), key)
-	if err != nil {
-		return nil, err
-	}
-	return x509.ParseCertificate(der)
+	dnsNames := preCert.DNSNames
+	if dnsNames == nil && preCert.Subject.CommonName != "" {
+		dnsNames = []string{preCert.Subject.CommonName}
+	}
+	return buildLeafCert(rootCert, key, key.Public(), CertOpts{
+		DNSNames:    dnsNames,
+		IPAddresses: preCert.IPAddresses,
+	})
 }
 
 func CreateProxyCertWithName(serverName string) (tlsCert tls.Certificate, err error) {
@@ -0,0 +1,103 @@
+package gtls
+
+import (
+	"fmt"
+	"net"
+
+	utls "github.com/refraction-networking/utls"
+)
+
+// fingerprintProfiles maps friendly fingerprint names to uTLS's built-in
+// ClientHelloID presets.
+var fingerprintProfiles = map[string]utls.ClientHelloID{
+	"chrome_120":     utls.HelloChrome_120,
+	"firefox_latest": utls.HelloFirefox_Auto,
+	"safari_16":      utls.HelloSafari_16_0,
+	"ios_14":         utls.HelloIOS_14,
+	"randomized":     utls.HelloRandomized,
+}
+
+// ClientHelloOverrides tweaks individual extensions on top of a base
+// fingerprint profile. Nil/empty fields leave the profile's own value alone.
+type ClientHelloOverrides struct {
+	ALPN                []string
+	SupportedVersions   []uint16
+	KeyShareGroups      []utls.CurveID
+	SignatureAlgorithms []utls.SignatureScheme
+}
+
+// ClientConfig resolves fingerprint (e.g. "chrome_120", "firefox_latest",
+// "safari_16", "ios_14", "randomized") to a ready-to-use uTLS *Config and its
+// matching ClientHelloID.
+func ClientConfig(fingerprint string) (*utls.Config, utls.ClientHelloID, error) {
+	id, ok := fingerprintProfiles[fingerprint]
+	if !ok {
+		return nil, utls.ClientHelloID{}, fmt.Errorf("gtls: unknown TLS fingerprint profile %q", fingerprint)
+	}
+	return &utls.Config{}, id, nil
+}
+
+// Dial connects to addr over network and performs a TLS handshake whose
+// ClientHello mimics fingerprint. The server name is taken from addr's host
+// portion.
+func Dial(network, addr, fingerprint string) (*utls.UConn, error) {
+	return DialWithOverrides(network, addr, fingerprint, ClientHelloOverrides{})
+}
+
+// DialWithOverrides is Dial plus the ability to tweak individual extensions
+// (ALPN, supported_versions, key_share groups, signature algorithms) on top
+// of fingerprint's base profile: it builds the ClientHello, applies
+// overrides to it, and only then sends it.
+func DialWithOverrides(network, addr, fingerprint string, overrides ClientHelloOverrides) (*utls.UConn, error) {
+	config, id, err := ClientConfig(fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	config.ServerName = GetServerName(addr)
+	rawConn, err := net.Dial(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	uconn := utls.UClient(rawConn, config, id)
+	if err := uconn.BuildHandshakeState(); err != nil {
+		rawConn.Close()
+		return nil, err
+	}
+	ApplyOverrides(uconn, overrides)
+	if err := uconn.Handshake(); err != nil {
+		rawConn.Close()
+		return nil, err
+	}
+	return uconn, nil
+}
+
+// ApplyOverrides replaces individual extensions already present on uconn's
+// ClientHello with overrides' values, layering them on top of whatever its
+// base fingerprint profile set. Call it after uconn.BuildHandshakeState and
+// before uconn.Handshake.
+func ApplyOverrides(uconn *utls.UConn, overrides ClientHelloOverrides) {
+	for _, ext := range uconn.Extensions {
+		switch e := ext.(type) {
+		case *utls.ALPNExtension:
+			if overrides.ALPN != nil {
+				e.AlpnProtocols = overrides.ALPN
+			}
+		case *utls.SupportedVersionsExtension:
+			if overrides.SupportedVersions != nil {
+				e.Versions = overrides.SupportedVersions
+			}
+		case *utls.KeyShareExtension:
+			if overrides.KeyShareGroups != nil {
+				shares := make([]utls.KeyShare, len(overrides.KeyShareGroups))
+				for i, group := range overrides.KeyShareGroups {
+					shares[i] = utls.KeyShare{Group: group}
+				}
+				e.KeyShares = shares
+			}
+		case *utls.SignatureAlgorithmsExtension:
+			if overrides.SignatureAlgorithms != nil {
+				e.SupportedSignatureAlgorithms = overrides.SignatureAlgorithms
+			}
+		}
+	}
+}